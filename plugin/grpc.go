@@ -3,26 +3,219 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"sync"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
 
 	"github.com/hashicorp/terraform/plugin/proto"
 	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform/tfdiags"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// translationError reports a bad wire payload as a gRPC Internal status
+// instead of panicking.
+func translationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Errorf(codes.Internal, "translating provider response: %s", err)
+}
+
+// ProtocolVersion is the version of the provider wire protocol implemented
+// by this package, sent to the provider in HandshakeRequest. The protocol
+// version the provider reports back is recorded in Capabilities; it is not
+// compared against this one.
+const ProtocolVersion = 5
+
 // terraform.ResourceProvider grpc implementation
 type GRPCResourceProvider struct {
 	conn   *grpc.ClientConn
 	client proto.ProviderClient
+
+	// UI, if set, receives progress messages streamed back from the
+	// provider during Apply and ReadDataApply (see resourceProviderApplier
+	// and dataSourceApplier).
+	UI terraform.UIOutput
+
+	// RequestTimeout, if non-zero, bounds each RPC made through
+	// requestContext, propagated as the context deadline; zero means no
+	// deadline beyond Stop's cancellation. Input, Stop and Ping do not use it.
+	RequestTimeout time.Duration
+
+	ctxOnce sync.Once
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// capsMu guards caps/capsOK; see Capabilities for the caching policy.
+	capsMu sync.Mutex
+	caps   proto.Capabilities
+	capsOK bool
+
+	// schemaMu guards the resourceTypes/dataSourceTypes schema cache below.
+	schemaMu        sync.Mutex
+	resourceTypes   map[string]cty.Type
+	dataSourceTypes map[string]cty.Type
+}
+
+// context returns the shared context used for all RPCs made by this
+// provider, creating it on first use. Stop cancels this context, which
+// aborts any RPC still in flight rather than letting it hang forever.
+func (p *GRPCResourceProvider) context() context.Context {
+	p.ctxOnce.Do(func() {
+		p.ctx, p.cancel = context.WithCancel(context.Background())
+	})
+	return p.ctx
+}
+
+// requestContext returns the context for a single RPC: the shared context
+// from context(), bounded by RequestTimeout when set. The returned cancel
+// must run once the RPC (and, for a streaming RPC, the whole stream) is
+// done; deferring it is safe in both cases, since with no RequestTimeout it
+// is a no-op func rather than nil.
+func (p *GRPCResourceProvider) requestContext() (context.Context, context.CancelFunc) {
+	if p.RequestTimeout <= 0 {
+		return p.context(), func() {}
+	}
+	return context.WithTimeout(p.context(), p.RequestTimeout)
 }
 
 func (p *GRPCResourceProvider) Stop() error {
-	_, err := p.client.Stop(context.TODO(), nil)
+	_, err := p.client.Stop(context.Background(), nil)
+	// Force ctxOnce to run if no RPC has populated p.cancel yet, so this
+	// read can't race the lazy initialization in context().
+	p.context()
+	p.cancel()
+	return err
+}
+
+// Capabilities returns the capability set negotiated with the provider via
+// the Handshake RPC, caching a successful result for the provider's
+// lifetime; ImportState uses it to refuse running against a provider that
+// doesn't report CapTypedValues. A failed handshake is retried on the next
+// call rather than latched, since the common cause is a transient timing
+// hiccup with the subprocess, not lasting incompatibility.
+func (p *GRPCResourceProvider) Capabilities() (proto.Capabilities, error) {
+	p.capsMu.Lock()
+	defer p.capsMu.Unlock()
+
+	if p.capsOK {
+		return p.caps, nil
+	}
+
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.Handshake(ctx, &proto.HandshakeRequest{
+		ProtocolVersion: ProtocolVersion,
+	})
+	if err != nil {
+		return proto.Capabilities{}, err
+	}
+
+	if resp.SupportedCapabilities != resp.ServerCapabilities {
+		log.Printf("[WARN] provider supports capabilities %#x but negotiated only %#x; honoring the negotiated set", resp.SupportedCapabilities, resp.ServerCapabilities)
+	}
+
+	p.caps = proto.Capabilities{
+		ProtocolVersion: resp.ProtocolVersion,
+		ProviderVersion: resp.ProviderVersion,
+		Supported:       resp.SupportedCapabilities,
+		Server:          resp.ServerCapabilities,
+	}
+	p.capsOK = true
+	return p.caps, nil
+}
+
+// pingTimeout bounds how long Ping waits for a wedged provider to respond.
+// A liveness probe that can itself block forever defeats its purpose.
+const pingTimeout = 5 * time.Second
+
+// Ping is a lightweight liveness check the plugin host can poll on an
+// interval to detect and reap a provider process that has wedged (accepted
+// the connection but stopped responding), rather than waiting for a real
+// RPC to time out.
+func (p *GRPCResourceProvider) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	_, err := p.client.Ping(ctx, new(proto.Empty))
 	return err
 }
 
+// resourceType returns the cty.Type implied by the named resource type's
+// schema, caching it since schemas don't change at runtime.
+func (p *GRPCResourceProvider) resourceType(name string) (cty.Type, error) {
+	p.schemaMu.Lock()
+	ty, ok := p.resourceTypes[name]
+	p.schemaMu.Unlock()
+	if ok {
+		return ty, nil
+	}
+
+	schema, err := p.GetSchema(&terraform.ProviderSchemaRequest{ResourceTypes: []string{name}})
+	if err != nil {
+		return cty.NilType, err
+	}
+
+	block, ok := schema.ResourceTypes[name]
+	if !ok {
+		return cty.NilType, fmt.Errorf("unknown resource type %q", name)
+	}
+
+	ty = block.ImpliedType()
+
+	p.schemaMu.Lock()
+	if p.resourceTypes == nil {
+		p.resourceTypes = make(map[string]cty.Type)
+	}
+	p.resourceTypes[name] = ty
+	p.schemaMu.Unlock()
+
+	return ty, nil
+}
+
+// dataSourceType is the data-source equivalent of resourceType.
+func (p *GRPCResourceProvider) dataSourceType(name string) (cty.Type, error) {
+	p.schemaMu.Lock()
+	ty, ok := p.dataSourceTypes[name]
+	p.schemaMu.Unlock()
+	if ok {
+		return ty, nil
+	}
+
+	schema, err := p.GetSchema(&terraform.ProviderSchemaRequest{DataSources: []string{name}})
+	if err != nil {
+		return cty.NilType, err
+	}
+
+	block, ok := schema.DataSources[name]
+	if !ok {
+		return cty.NilType, fmt.Errorf("unknown data source %q", name)
+	}
+
+	ty = block.ImpliedType()
+
+	p.schemaMu.Lock()
+	if p.dataSourceTypes == nil {
+		p.dataSourceTypes = make(map[string]cty.Type)
+	}
+	p.dataSourceTypes[name] = ty
+	p.schemaMu.Unlock()
+
+	return ty, nil
+}
+
 func (p *GRPCResourceProvider) GetSchema(req *terraform.ProviderSchemaRequest) (*terraform.ProviderSchema, error) {
-	resp, err := p.client.GetSchema(context.TODO(), &proto.GetSchemaRequest{
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.GetSchema(ctx, &proto.GetSchemaRequest{
 		ResourceTypes: req.ResourceTypes,
 		DataSources:   req.DataSources,
 	})
@@ -40,12 +233,17 @@ func (p *GRPCResourceProvider) GetSchema(req *terraform.ProviderSchemaRequest) (
 }
 
 func (p *GRPCResourceProvider) Input(input terraform.UIInput, c *terraform.ResourceConfig) (*terraform.ResourceConfig, error) {
+	cfg, err := proto.NewResourceConfig(c, cty.DynamicPseudoType)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &proto.InputRequest{
-		ResourceConfig: proto.NewResourceConfig(c),
+		ResourceConfig: cfg,
 	}
 
 	// Open an input stream with the plugin
-	inputClient, err := p.client.Input(context.TODO())
+	inputClient, err := p.client.Input(p.context())
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +264,10 @@ func (p *GRPCResourceProvider) Input(input terraform.UIInput, c *terraform.Resou
 		}
 
 		if resp.ResourceConfig != nil {
-			rc = resp.ResourceConfig.TFResourceConfig()
+			rc, err = resp.ResourceConfig.TFResourceConfig(cty.DynamicPseudoType)
+			if err != nil {
+				return nil, err
+			}
 			break
 		}
 
@@ -91,116 +292,250 @@ func (p *GRPCResourceProvider) Input(input terraform.UIInput, c *terraform.Resou
 	return rc, nil
 }
 
-func (p *GRPCResourceProvider) Validate(c *terraform.ResourceConfig) ([]string, []error) {
+func (p *GRPCResourceProvider) Validate(c *terraform.ResourceConfig) tfdiags.Diagnostics {
+	cfg, err := proto.NewResourceConfig(c, cty.DynamicPseudoType)
+	if err != nil {
+		return tfdiags.Diagnostics{{Summary: "failed to encode config", Detail: err.Error()}}
+	}
+
 	req := &proto.ValidateRequest{
-		Config: proto.NewResourceConfig(c),
+		Config: cfg,
 	}
-	resp, err := p.client.Validate(context.TODO(), req)
+
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.Validate(ctx, req)
 	if err != nil {
-		return nil, []error{err}
+		return tfdiags.Diagnostics{{Summary: err.Error()}}
 	}
 
-	return resp.Warnings, resp.ErrorList()
+	return proto.ToDiagnostics(resp.Diagnostics)
 }
 
-func (p *GRPCResourceProvider) ValidateResource(t string, c *terraform.ResourceConfig) ([]string, []error) {
+func (p *GRPCResourceProvider) ValidateResource(t string, c *terraform.ResourceConfig) tfdiags.Diagnostics {
+	ty, err := p.resourceType(t)
+	if err != nil {
+		return tfdiags.Diagnostics{{Summary: err.Error()}}
+	}
+
+	cfg, err := proto.NewResourceConfig(c, ty)
+	if err != nil {
+		return tfdiags.Diagnostics{{Summary: "failed to encode config", Detail: err.Error()}}
+	}
 
 	req := &proto.ValidateResourceRequest{
 		Type:   t,
-		Config: proto.NewResourceConfig(c),
+		Config: cfg,
 	}
 
-	resp, err := p.client.ValidateResource(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.ValidateResource(ctx, req)
 	if err != nil {
-		return nil, []error{err}
+		return tfdiags.Diagnostics{{Summary: err.Error()}}
 	}
 
-	return resp.Warnings, resp.ErrorList()
+	return proto.ToDiagnostics(resp.Diagnostics)
 }
 
 func (p *GRPCResourceProvider) Configure(c *terraform.ResourceConfig) error {
+	cfg, err := proto.NewResourceConfig(c, cty.DynamicPseudoType)
+	if err != nil {
+		return err
+	}
+
 	req := &proto.ConfigureRequest{
-		ResourceConfig: proto.NewResourceConfig(c),
+		ResourceConfig: cfg,
 	}
 
-	_, err := p.client.Configure(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	_, err = p.client.Configure(ctx, req)
 	return err
 }
 
+// Apply reads from the server-streaming Apply RPC, forwarding any
+// intermediate Output messages to p.UI and returning the final state carried
+// by the last message on the stream. Whether any intermediate messages
+// actually arrive depends on the provider (see GRPCResourceProvider.UI);
+// against one that doesn't emit progress this loop still runs once before
+// returning.
 func (p *GRPCResourceProvider) Apply(info *terraform.InstanceInfo, s *terraform.InstanceState, d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+	ty, err := p.resourceType(info.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := proto.NewInstanceState(s, ty)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := proto.NewInstanceDiff(d)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &proto.ApplyRequest{
 		Info:  proto.NewInstanceInfo(info),
-		State: proto.NewInstanceState(s),
-		Diff:  proto.NewInstanceDiff(d),
+		State: state,
+		Diff:  diff,
 	}
 
-	resp, err := p.client.Apply(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	applyClient, err := p.client.Apply(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.State.TFInstanceState(), nil
+	for {
+		resp, err := applyClient.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		// resp.Output having arrived at all is proof it should be shown;
+		// don't re-gate it on Capabilities(), which can zero-value on a
+		// transient Handshake failure unrelated to this already-streamed
+		// message.
+		if resp.Output != nil {
+			if p.UI != nil {
+				p.UI.Output(resp.Output.Message)
+			}
+			continue
+		}
+
+		return resp.State.TFInstanceState(ty)
+	}
 }
 
 func (p *GRPCResourceProvider) Diff(info *terraform.InstanceInfo, s *terraform.InstanceState, c *terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+	ty, err := p.resourceType(info.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := proto.NewInstanceState(s, ty)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := proto.NewResourceConfig(c, ty)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &proto.DiffRequest{
 		Info:   proto.NewInstanceInfo(info),
-		State:  proto.NewInstanceState(s),
-		Config: proto.NewResourceConfig(c),
+		State:  state,
+		Config: cfg,
 	}
 
-	resp, err := p.client.Diff(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.Diff(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.Diff.TFInstanceDiff(), nil
+	return resp.Diff.TFInstanceDiff()
 }
 
-func (p *GRPCResourceProvider) ValidateDataSource(t string, c *terraform.ResourceConfig) ([]string, []error) {
+func (p *GRPCResourceProvider) ValidateDataSource(t string, c *terraform.ResourceConfig) tfdiags.Diagnostics {
+	ty, err := p.dataSourceType(t)
+	if err != nil {
+		return tfdiags.Diagnostics{{Summary: err.Error()}}
+	}
+
+	cfg, err := proto.NewResourceConfig(c, ty)
+	if err != nil {
+		return tfdiags.Diagnostics{{Summary: "failed to encode config", Detail: err.Error()}}
+	}
+
 	req := &proto.ValidateDataSourceRequest{
 		Type:   t,
-		Config: proto.NewResourceConfig(c),
+		Config: cfg,
 	}
 
-	resp, err := p.client.ValidateDataSource(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.ValidateDataSource(ctx, req)
 	if err != nil {
-		return nil, []error{err}
+		return tfdiags.Diagnostics{{Summary: err.Error()}}
 	}
 
-	return resp.Warnings, resp.ErrorList()
+	return proto.ToDiagnostics(resp.Diagnostics)
 }
 
 func (p *GRPCResourceProvider) Refresh(info *terraform.InstanceInfo, s *terraform.InstanceState) (*terraform.InstanceState, error) {
+	ty, err := p.resourceType(info.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := proto.NewInstanceState(s, ty)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &proto.RefreshRequest{
 		Info:  proto.NewInstanceInfo(info),
-		State: proto.NewInstanceState(s),
+		State: state,
 	}
 
-	resp, err := p.client.Refresh(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.Refresh(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.State.TFInstanceState(), nil
+	return resp.State.TFInstanceState(ty)
 }
 
 func (p *GRPCResourceProvider) ImportState(info *terraform.InstanceInfo, id string) ([]*terraform.InstanceState, error) {
+	caps, err := p.Capabilities()
+	if err != nil {
+		return nil, err
+	}
+	if caps.Server&proto.CapTypedValues == 0 {
+		return nil, fmt.Errorf("provider does not support the typed value protocol required for ImportState")
+	}
+
+	ty, err := p.resourceType(info.Type)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &proto.ImportStateRequest{
 		Id:   id,
 		Info: proto.NewInstanceInfo(info),
 	}
 
-	resp, err := p.client.ImportState(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.ImportState(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.TFInstanceStates(), nil
+	return resp.TFInstanceStates(ty)
 }
 
 func (p *GRPCResourceProvider) Resources() []terraform.ResourceType {
-	resp, err := p.client.Resources(context.TODO(), nil)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.Resources(ctx, nil)
 	if err != nil {
 		log.Println("[ERROR]", err)
 		return nil
@@ -210,35 +545,83 @@ func (p *GRPCResourceProvider) Resources() []terraform.ResourceType {
 }
 
 func (p *GRPCResourceProvider) ReadDataDiff(info *terraform.InstanceInfo, c *terraform.ResourceConfig) (*terraform.InstanceDiff, error) {
+	ty, err := p.dataSourceType(info.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := proto.NewResourceConfig(c, ty)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &proto.ReadDataDiffRequest{
 		Info:   proto.NewInstanceInfo(info),
-		Config: proto.NewResourceConfig(c),
+		Config: cfg,
 	}
 
-	resp, err := p.client.ReadDataDiff(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.ReadDataDiff(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.Diff.TFInstanceDiff(), nil
+	return resp.Diff.TFInstanceDiff()
 }
 
+// ReadDataApply reads from the stream the same way Apply does.
 func (p *GRPCResourceProvider) ReadDataApply(info *terraform.InstanceInfo, d *terraform.InstanceDiff) (*terraform.InstanceState, error) {
+	ty, err := p.dataSourceType(info.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := proto.NewInstanceDiff(d)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &proto.ReadDataApplyRequest{
 		Info: proto.NewInstanceInfo(info),
-		Diff: proto.NewInstanceDiff(d),
+		Diff: diff,
 	}
 
-	resp, err := p.client.ReadDataApply(context.TODO(), req)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	applyClient, err := p.client.ReadDataApply(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.State.TFInstanceState(), nil
+	for {
+		resp, err := applyClient.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		// resp.Output having arrived at all is proof it should be shown;
+		// don't re-gate it on Capabilities(), which can zero-value on a
+		// transient Handshake failure unrelated to this already-streamed
+		// message.
+		if resp.Output != nil {
+			if p.UI != nil {
+				p.UI.Output(resp.Output.Message)
+			}
+			continue
+		}
+
+		return resp.State.TFInstanceState(ty)
+	}
 }
 
 func (p *GRPCResourceProvider) DataSources() []terraform.DataSource {
-	resp, err := p.client.DataSources(context.TODO(), nil)
+	ctx, cancel := p.requestContext()
+	defer cancel()
+
+	resp, err := p.client.DataSources(ctx, nil)
 	if err != nil {
 		log.Println("[ERROR]", err)
 	}
@@ -247,16 +630,62 @@ func (p *GRPCResourceProvider) DataSources() []terraform.DataSource {
 }
 
 func (p *GRPCResourceProvider) Close() error {
-	return nil
 	return p.conn.Close()
 }
 
 type GRPCResourceProviderServer struct {
 	provider terraform.ResourceProvider
+
+	ctxOnce sync.Once
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// schemaMu guards resourceTypes and dataSourceTypes, the server-side
+	// counterpart of GRPCResourceProvider's type cache.
+	schemaMu        sync.Mutex
+	resourceTypes   map[string]cty.Type
+	dataSourceTypes map[string]cty.Type
+}
+
+// context returns the context governing this server's long-running RPCs,
+// creating it on first use. Stop cancels it; Apply and ReadDataApply check it
+// once before starting and refuse to run once it is cancelled, but an
+// operation already running in the provider is not interrupted.
+func (s *GRPCResourceProviderServer) context() context.Context {
+	s.ctxOnce.Do(func() {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	})
+	return s.ctx
+}
+
+// serverCapabilities is the fixed set of protocol features this version of
+// the gRPC server implements, reported to the host during Handshake.
+const serverCapabilities = proto.CapTypedValues | proto.CapStreaming | proto.CapPrivateState | proto.CapDiagnostics
+
+// Handshake negotiates the protocol version and capability bitmasks before
+// any other RPC is made. SupportedCapabilities and ServerCapabilities are
+// reported separately to leave room for a future server with narrower
+// support than this package's full feature set.
+func (s *GRPCResourceProviderServer) Handshake(_ context.Context, _ *proto.HandshakeRequest) (*proto.HandshakeResponse, error) {
+	return &proto.HandshakeResponse{
+		ProtocolVersion:       ProtocolVersion,
+		SupportedCapabilities: serverCapabilities,
+		ServerCapabilities:    serverCapabilities,
+	}, nil
+}
+
+// Ping is the server side of GRPCResourceProvider.Ping.
+func (s *GRPCResourceProviderServer) Ping(_ context.Context, _ *proto.Empty) (*proto.Empty, error) {
+	return new(proto.Empty), nil
 }
 
 func (s *GRPCResourceProviderServer) Stop(_ context.Context, _ *proto.Empty) (*proto.Empty, error) {
-	return new(proto.Empty), s.provider.Stop()
+	err := s.provider.Stop()
+	// Force ctxOnce to run if no RPC has populated s.cancel yet, so this
+	// read can't race the lazy initialization in context().
+	s.context()
+	s.cancel()
+	return new(proto.Empty), err
 }
 
 func (s *GRPCResourceProviderServer) GetSchema(_ context.Context, req *proto.GetSchemaRequest) (*proto.GetSchemaResponse, error) {
@@ -279,19 +708,92 @@ func (s *GRPCResourceProviderServer) GetSchema(_ context.Context, req *proto.Get
 
 }
 
+// resourceType is the server-side equivalent of GRPCResourceProvider.resourceType,
+// caching each resource type's implied type on first lookup.
+func (s *GRPCResourceProviderServer) resourceType(name string) (cty.Type, error) {
+	s.schemaMu.Lock()
+	ty, ok := s.resourceTypes[name]
+	s.schemaMu.Unlock()
+	if ok {
+		return ty, nil
+	}
+
+	schema, err := s.provider.GetSchema(&terraform.ProviderSchemaRequest{ResourceTypes: []string{name}})
+	if err != nil {
+		return cty.NilType, err
+	}
+
+	block, ok := schema.ResourceTypes[name]
+	if !ok {
+		return cty.NilType, fmt.Errorf("unknown resource type %q", name)
+	}
+
+	ty = block.ImpliedType()
+
+	s.schemaMu.Lock()
+	if s.resourceTypes == nil {
+		s.resourceTypes = make(map[string]cty.Type)
+	}
+	s.resourceTypes[name] = ty
+	s.schemaMu.Unlock()
+
+	return ty, nil
+}
+
+// dataSourceType is the server-side equivalent of GRPCResourceProvider.dataSourceType,
+// caching each data source's implied type on first lookup.
+func (s *GRPCResourceProviderServer) dataSourceType(name string) (cty.Type, error) {
+	s.schemaMu.Lock()
+	ty, ok := s.dataSourceTypes[name]
+	s.schemaMu.Unlock()
+	if ok {
+		return ty, nil
+	}
+
+	schema, err := s.provider.GetSchema(&terraform.ProviderSchemaRequest{DataSources: []string{name}})
+	if err != nil {
+		return cty.NilType, err
+	}
+
+	block, ok := schema.DataSources[name]
+	if !ok {
+		return cty.NilType, fmt.Errorf("unknown data source %q", name)
+	}
+
+	ty = block.ImpliedType()
+
+	s.schemaMu.Lock()
+	if s.dataSourceTypes == nil {
+		s.dataSourceTypes = make(map[string]cty.Type)
+	}
+	s.dataSourceTypes[name] = ty
+	s.schemaMu.Unlock()
+
+	return ty, nil
+}
+
 func (s *GRPCResourceProviderServer) Input(server proto.Provider_InputServer) error {
 	req, err := server.Recv()
 	if err != nil {
 		return err
 	}
 
-	rc := req.ResourceConfig.TFResourceConfig()
+	rc, err := req.ResourceConfig.TFResourceConfig(cty.DynamicPseudoType)
+	if err != nil {
+		return err
+	}
+
 	rc, err = s.provider.Input(&grpcInputServer{server: server}, rc)
 	if err != nil {
 		return err
 	}
 
-	return server.Send(&proto.InputResponse{ResourceConfig: proto.NewResourceConfig(rc)})
+	cfg, err := proto.NewResourceConfig(rc, cty.DynamicPseudoType)
+	if err != nil {
+		return err
+	}
+
+	return server.Send(&proto.InputResponse{ResourceConfig: cfg})
 }
 
 type grpcInputServer struct {
@@ -320,52 +822,172 @@ func (s *grpcInputServer) Input(opts *terraform.InputOpts) (string, error) {
 }
 
 func (s *GRPCResourceProviderServer) Validate(_ context.Context, req *proto.ValidateRequest) (*proto.ValidateResponse, error) {
-	w, e := s.provider.Validate(req.Config.TFResourceConfig())
-	return proto.NewValidateResponse(w, e), nil
+	c, err := req.Config.TFResourceConfig(cty.DynamicPseudoType)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	diags := s.provider.Validate(c)
+	return &proto.ValidateResponse{Diagnostics: proto.NewDiagnostics(diags)}, nil
 }
 
 func (s *GRPCResourceProviderServer) ValidateResource(_ context.Context, req *proto.ValidateResourceRequest) (*proto.ValidateResponse, error) {
-	w, e := s.provider.ValidateResource(req.Type, req.Config.TFResourceConfig())
-	return proto.NewValidateResponse(w, e), nil
+	ty, err := s.resourceType(req.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := req.Config.TFResourceConfig(ty)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	diags := s.provider.ValidateResource(req.Type, c)
+	return &proto.ValidateResponse{Diagnostics: proto.NewDiagnostics(diags)}, nil
 }
 
 func (s *GRPCResourceProviderServer) Configure(_ context.Context, req *proto.ConfigureRequest) (*proto.Empty, error) {
-	err := s.provider.Configure(req.ResourceConfig.TFResourceConfig())
+	c, err := req.ResourceConfig.TFResourceConfig(cty.DynamicPseudoType)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	err = s.provider.Configure(c)
 	return new(proto.Empty), err
 }
 
-func (s *GRPCResourceProviderServer) Apply(_ context.Context, req *proto.ApplyRequest) (*proto.ApplyResponse, error) {
-	is, err := s.provider.Apply(req.Info.TFInstanceInfo(), req.State.TFInstanceState(), req.Diff.TFInstanceDiff())
+// resourceProviderApplier is an optional interface a terraform.ResourceProvider
+// can implement alongside its required synchronous Apply to report real
+// incremental progress. GRPCResourceProviderServer.Apply checks for it via a
+// type assertion; a provider that doesn't implement it still gets exactly
+// one response message.
+type resourceProviderApplier interface {
+	ApplyWithUI(out terraform.UIOutput, info *terraform.InstanceInfo, s *terraform.InstanceState, d *terraform.InstanceDiff) (*terraform.InstanceState, error)
+}
+
+// grpcApplyOutputServer adapts the server-streaming Apply RPC to
+// terraform.UIOutput so a resourceProviderApplier can stream Output
+// messages ahead of its final state.
+type grpcApplyOutputServer struct {
+	stream proto.Provider_ApplyServer
+}
+
+func (s *grpcApplyOutputServer) Output(msg string) {
+	s.stream.Send(&proto.ApplyResponse{Output: &proto.UIOutput{Message: msg}})
+}
+
+// Apply streams the final state back as a single message, unless the
+// provider implements resourceProviderApplier, in which case its progress
+// messages are streamed ahead of the final one.
+func (s *GRPCResourceProviderServer) Apply(req *proto.ApplyRequest, stream proto.Provider_ApplyServer) error {
+	if err := s.context().Err(); err != nil {
+		return err
+	}
+
+	ty, err := s.resourceType(req.Info.Type)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	state, err := req.State.TFInstanceState(ty)
+	if err != nil {
+		return translationError(err)
+	}
+
+	diff, err := req.Diff.TFInstanceDiff()
+	if err != nil {
+		return translationError(err)
+	}
+
+	info := req.Info.TFInstanceInfo()
+
+	var is *terraform.InstanceState
+	if applier, ok := s.provider.(resourceProviderApplier); ok {
+		is, err = applier.ApplyWithUI(&grpcApplyOutputServer{stream: stream}, info, state, diff)
+	} else {
+		is, err = s.provider.Apply(info, state, diff)
+	}
+	if err != nil {
+		return err
+	}
+
+	newState, err := proto.NewInstanceState(is, ty)
+	if err != nil {
+		return translationError(err)
 	}
 
-	return &proto.ApplyResponse{State: proto.NewInstanceState(is)}, nil
+	return stream.Send(&proto.ApplyResponse{State: newState})
 }
 
 func (s *GRPCResourceProviderServer) Diff(_ context.Context, req *proto.DiffRequest) (*proto.DiffResponse, error) {
-	d, err := s.provider.Diff(req.Info.TFInstanceInfo(), req.State.TFInstanceState(), req.Config.TFResourceConfig())
+	ty, err := s.resourceType(req.Info.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := req.State.TFInstanceState(ty)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	config, err := req.Config.TFResourceConfig(ty)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	d, err := s.provider.Diff(req.Info.TFInstanceInfo(), state, config)
 	if err != nil {
 		return nil, err
 	}
-	return &proto.DiffResponse{Diff: proto.NewInstanceDiff(d)}, nil
+
+	diff, err := proto.NewInstanceDiff(d)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	return &proto.DiffResponse{Diff: diff}, nil
 }
 
 func (s *GRPCResourceProviderServer) Refresh(_ context.Context, req *proto.RefreshRequest) (*proto.RefreshResponse, error) {
-	is, err := s.provider.Refresh(req.Info.TFInstanceInfo(), req.State.TFInstanceState())
+	ty, err := s.resourceType(req.Info.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := req.State.TFInstanceState(ty)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	is, err := s.provider.Refresh(req.Info.TFInstanceInfo(), state)
 	if err != nil {
 		return nil, err
 	}
-	return &proto.RefreshResponse{State: proto.NewInstanceState(is)}, nil
+
+	newState, err := proto.NewInstanceState(is, ty)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	return &proto.RefreshResponse{State: newState}, nil
 }
 
 func (s *GRPCResourceProviderServer) ImportState(_ context.Context, req *proto.ImportStateRequest) (*proto.ImportStateResponse, error) {
+	ty, err := s.resourceType(req.Info.Type)
+	if err != nil {
+		return nil, err
+	}
+
 	states, err := s.provider.ImportState(req.Info.TFInstanceInfo(), req.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	return proto.NewImportStateResponse(states), nil
+	resp, err := proto.NewImportStateResponse(states, ty)
+	if err != nil {
+		return nil, translationError(err)
+	}
+	return resp, nil
 }
 
 func (s *GRPCResourceProviderServer) Resources(_ context.Context, _ *proto.Empty) (*proto.ResourcesResponse, error) {
@@ -373,25 +995,94 @@ func (s *GRPCResourceProviderServer) Resources(_ context.Context, _ *proto.Empty
 }
 
 func (s *GRPCResourceProviderServer) ValidateDataSource(_ context.Context, req *proto.ValidateDataSourceRequest) (*proto.ValidateResponse, error) {
-	w, e := s.provider.ValidateDataSource(req.Type, req.Config.TFResourceConfig())
-	return proto.NewValidateResponse(w, e), nil
+	ty, err := s.dataSourceType(req.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := req.Config.TFResourceConfig(ty)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	diags := s.provider.ValidateDataSource(req.Type, c)
+	return &proto.ValidateResponse{Diagnostics: proto.NewDiagnostics(diags)}, nil
 }
 
 func (s *GRPCResourceProviderServer) ReadDataDiff(_ context.Context, req *proto.ReadDataDiffRequest) (*proto.ReadDataDiffResponse, error) {
-	diff, err := s.provider.ReadDataDiff(req.Info.TFInstanceInfo(), req.Config.TFResourceConfig())
+	ty, err := s.dataSourceType(req.Info.Type)
 	if err != nil {
 		return nil, err
 	}
 
-	return &proto.ReadDataDiffResponse{Diff: proto.NewInstanceDiff(diff)}, nil
-}
+	config, err := req.Config.TFResourceConfig(ty)
+	if err != nil {
+		return nil, translationError(err)
+	}
 
-func (s *GRPCResourceProviderServer) ReadDataApply(_ context.Context, req *proto.ReadDataApplyRequest) (*proto.ReadDataApplyResponse, error) {
-	state, err := s.provider.ReadDataApply(req.Info.TFInstanceInfo(), req.Diff.TFInstanceDiff())
+	diff, err := s.provider.ReadDataDiff(req.Info.TFInstanceInfo(), config)
 	if err != nil {
 		return nil, err
 	}
-	return &proto.ReadDataApplyResponse{State: proto.NewInstanceState(state)}, nil
+
+	d, err := proto.NewInstanceDiff(diff)
+	if err != nil {
+		return nil, translationError(err)
+	}
+
+	return &proto.ReadDataDiffResponse{Diff: d}, nil
+}
+
+// dataSourceApplier is the ReadDataApply equivalent of resourceProviderApplier.
+type dataSourceApplier interface {
+	ReadDataApplyWithUI(out terraform.UIOutput, info *terraform.InstanceInfo, d *terraform.InstanceDiff) (*terraform.InstanceState, error)
+}
+
+// grpcReadDataApplyOutputServer is the ReadDataApply equivalent of
+// grpcApplyOutputServer.
+type grpcReadDataApplyOutputServer struct {
+	stream proto.Provider_ReadDataApplyServer
+}
+
+func (s *grpcReadDataApplyOutputServer) Output(msg string) {
+	s.stream.Send(&proto.ReadDataApplyResponse{Output: &proto.UIOutput{Message: msg}})
+}
+
+// ReadDataApply streams the final state back the same way Apply does,
+// including the dataSourceApplier opt-in for real progress.
+func (s *GRPCResourceProviderServer) ReadDataApply(req *proto.ReadDataApplyRequest, stream proto.Provider_ReadDataApplyServer) error {
+	if err := s.context().Err(); err != nil {
+		return err
+	}
+
+	ty, err := s.dataSourceType(req.Info.Type)
+	if err != nil {
+		return err
+	}
+
+	diff, err := req.Diff.TFInstanceDiff()
+	if err != nil {
+		return translationError(err)
+	}
+
+	info := req.Info.TFInstanceInfo()
+
+	var state *terraform.InstanceState
+	if applier, ok := s.provider.(dataSourceApplier); ok {
+		state, err = applier.ReadDataApplyWithUI(&grpcReadDataApplyOutputServer{stream: stream}, info, diff)
+	} else {
+		state, err = s.provider.ReadDataApply(info, diff)
+	}
+	if err != nil {
+		return err
+	}
+
+	newState, err := proto.NewInstanceState(state, ty)
+	if err != nil {
+		return translationError(err)
+	}
+
+	return stream.Send(&proto.ReadDataApplyResponse{State: newState})
 }
 
 func (s *GRPCResourceProviderServer) DataSources(_ context.Context, _ *proto.Empty) (*proto.DataSourcesResponse, error) {
@@ -405,8 +1096,13 @@ type GRPCResourceProvisioner struct {
 }
 
 func (p *GRPCResourceProvisioner) Validate(c *terraform.ResourceConfig) ([]string, []error) {
+	cfg, err := proto.NewResourceConfig(c, cty.DynamicPseudoType)
+	if err != nil {
+		return nil, []error{err}
+	}
+
 	req := &proto.ValidateRequest{
-		Config: proto.NewResourceConfig(c),
+		Config: cfg,
 	}
 	resp, err := p.client.Validate(context.TODO(), req)
 	if err != nil {
@@ -417,9 +1113,19 @@ func (p *GRPCResourceProvisioner) Validate(c *terraform.ResourceConfig) ([]strin
 }
 
 func (p *GRPCResourceProvisioner) Apply(out terraform.UIOutput, s *terraform.InstanceState, c *terraform.ResourceConfig) error {
+	state, err := proto.NewInstanceState(s, cty.DynamicPseudoType)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := proto.NewResourceConfig(c, cty.DynamicPseudoType)
+	if err != nil {
+		return err
+	}
+
 	req := &proto.ProvisionerApplyRequest{
-		State:  proto.NewInstanceState(s),
-		Config: proto.NewResourceConfig(c),
+		State:  state,
+		Config: cfg,
 	}
 
 	outputClient, err := p.client.Apply(context.TODO(), req)
@@ -448,12 +1154,27 @@ type GRPCResourceProvisionerServer struct {
 }
 
 func (s *GRPCResourceProvisionerServer) Validate(_ context.Context, req *proto.ValidateRequest) (*proto.ValidateResponse, error) {
-	w, e := s.provisioner.Validate(req.Config.TFResourceConfig())
+	c, err := req.Config.TFResourceConfig(cty.DynamicPseudoType)
+	if err != nil {
+		return nil, err
+	}
+
+	w, e := s.provisioner.Validate(c)
 	return proto.NewValidateResponse(w, e), nil
 }
 
 func (s *GRPCResourceProvisionerServer) Apply(req *proto.ProvisionerApplyRequest, server proto.Provisioner_ApplyServer) error {
-	return s.provisioner.Apply(&grpcOutputServer{server: server}, req.State.TFInstanceState(), req.Config.TFResourceConfig())
+	state, err := req.State.TFInstanceState(cty.DynamicPseudoType)
+	if err != nil {
+		return err
+	}
+
+	config, err := req.Config.TFResourceConfig(cty.DynamicPseudoType)
+	if err != nil {
+		return err
+	}
+
+	return s.provisioner.Apply(&grpcOutputServer{server: server}, state, config)
 }
 
 func (s *GRPCResourceProvisionerServer) Stop(_ context.Context, _ *proto.Empty) (*proto.Empty, error) {
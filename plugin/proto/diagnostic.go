@@ -0,0 +1,175 @@
+package proto
+
+import (
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+// Severity mirrors tfdiags.Severity on the wire.
+type Severity int32
+
+const (
+	Diagnostic_ERROR   Severity = 0
+	Diagnostic_WARNING Severity = 1
+)
+
+// Diagnostic is the wire representation of a tfdiags.Diagnostic: a
+// severity, a human-oriented summary/detail pair, and the attribute path
+// and source range that produced it, if any.
+type Diagnostic struct {
+	Severity      Severity
+	Summary       string
+	Detail        string
+	AttributePath []*AttributePath_Step
+	Range         *Range
+}
+
+// StepKind discriminates which field of AttributePath_Step is meaningful.
+// It exists because the fields can't be told apart by zero-value alone: an
+// index step with an empty string key (foo[""]) and an attribute step with
+// no name set are both indistinguishable from "unset" without it.
+type StepKind int32
+
+const (
+	StepAttributeName    StepKind = 0
+	StepElementKeyString StepKind = 1
+	StepElementKeyInt    StepKind = 2
+)
+
+// AttributePath_Step is one step (attribute name or collection index) of a
+// path to a specific attribute within a resource's configuration.
+type AttributePath_Step struct {
+	// Kind says which of the following fields is meaningful, playing the
+	// role of a oneof.
+	Kind             StepKind
+	AttributeName    string
+	ElementKeyString string
+	ElementKeyInt    int64
+}
+
+// Range identifies a span within a source file.
+type Range struct {
+	Filename   string
+	Start, End Pos
+}
+
+// Pos is a single line/column/byte position within a source file.
+type Pos struct {
+	Line, Column, Byte int64
+}
+
+// NewDiagnostics converts a tfdiags.Diagnostics to its wire representation.
+func NewDiagnostics(diags tfdiags.Diagnostics) []*Diagnostic {
+	if len(diags) == 0 {
+		return nil
+	}
+
+	result := make([]*Diagnostic, 0, len(diags))
+	for _, diag := range diags {
+		result = append(result, newDiagnostic(diag))
+	}
+	return result
+}
+
+func newDiagnostic(diag *tfdiags.Diagnostic) *Diagnostic {
+	d := &Diagnostic{
+		Summary: diag.Summary,
+		Detail:  diag.Detail,
+	}
+
+	if diag.Severity == tfdiags.Warning {
+		d.Severity = Diagnostic_WARNING
+	} else {
+		d.Severity = Diagnostic_ERROR
+	}
+
+	for _, step := range diag.AttributePath {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			d.AttributePath = append(d.AttributePath, &AttributePath_Step{
+				Kind:          StepAttributeName,
+				AttributeName: s.Name,
+			})
+		case cty.IndexStep:
+			switch s.Key.Type() {
+			case cty.String:
+				d.AttributePath = append(d.AttributePath, &AttributePath_Step{
+					Kind:             StepElementKeyString,
+					ElementKeyString: s.Key.AsString(),
+				})
+			case cty.Number:
+				i, _ := s.Key.AsBigFloat().Int64()
+				d.AttributePath = append(d.AttributePath, &AttributePath_Step{
+					Kind:          StepElementKeyInt,
+					ElementKeyInt: i,
+				})
+			}
+		}
+	}
+
+	if diag.Subject != nil {
+		d.Range = &Range{
+			Filename: diag.Subject.Filename,
+			Start:    newPos(diag.Subject.Start),
+			End:      newPos(diag.Subject.End),
+		}
+	}
+
+	return d
+}
+
+func newPos(p tfdiags.SourcePos) Pos {
+	return Pos{Line: int64(p.Line), Column: int64(p.Column), Byte: int64(p.Byte)}
+}
+
+// ToDiagnostics is the inverse of NewDiagnostics.
+func ToDiagnostics(ds []*Diagnostic) tfdiags.Diagnostics {
+	if len(ds) == 0 {
+		return nil
+	}
+
+	diags := make(tfdiags.Diagnostics, 0, len(ds))
+	for _, d := range ds {
+		diags = append(diags, d.toDiagnostic())
+	}
+	return diags
+}
+
+func (d *Diagnostic) toDiagnostic() *tfdiags.Diagnostic {
+	diag := &tfdiags.Diagnostic{
+		Summary: d.Summary,
+		Detail:  d.Detail,
+	}
+
+	if d.Severity == Diagnostic_WARNING {
+		diag.Severity = tfdiags.Warning
+	} else {
+		diag.Severity = tfdiags.Error
+	}
+
+	for _, step := range d.AttributePath {
+		switch step.Kind {
+		case StepAttributeName:
+			diag.AttributePath = append(diag.AttributePath, cty.GetAttrStep{Name: step.AttributeName})
+		case StepElementKeyString:
+			diag.AttributePath = append(diag.AttributePath, cty.IndexStep{Key: cty.StringVal(step.ElementKeyString)})
+		case StepElementKeyInt:
+			diag.AttributePath = append(diag.AttributePath, cty.IndexStep{Key: cty.NumberIntVal(step.ElementKeyInt)})
+		}
+	}
+
+	if d.Range != nil {
+		diag.Subject = &tfdiags.SourceRange{
+			Filename: d.Range.Filename,
+			Start:    toSourcePos(d.Range.Start),
+			End:      toSourcePos(d.Range.End),
+		}
+	}
+
+	return diag
+}
+
+func toSourcePos(p Pos) tfdiags.SourcePos {
+	return tfdiags.SourcePos{Line: int(p.Line), Column: int(p.Column), Byte: int(p.Byte)}
+}
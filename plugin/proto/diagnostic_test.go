@@ -0,0 +1,74 @@
+package proto
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/hashicorp/terraform/tfdiags"
+)
+
+func TestDiagnosticsRoundTrip(t *testing.T) {
+	diags := tfdiags.Diagnostics{
+		{
+			Severity: tfdiags.Error,
+			Summary:  "bad config",
+			Detail:   "something is wrong",
+			AttributePath: cty.Path{
+				cty.GetAttrStep{Name: "tags"},
+				cty.IndexStep{Key: cty.StringVal("")},
+				cty.GetAttrStep{Name: "name"},
+				cty.IndexStep{Key: cty.NumberIntVal(2)},
+			},
+			Subject: &tfdiags.SourceRange{
+				Filename: "main.tf",
+				Start:    tfdiags.SourcePos{Line: 1, Column: 2, Byte: 3},
+				End:      tfdiags.SourcePos{Line: 1, Column: 5, Byte: 6},
+			},
+		},
+		{
+			Severity: tfdiags.Warning,
+			Summary:  "deprecated",
+		},
+	}
+
+	got := ToDiagnostics(NewDiagnostics(diags))
+
+	if len(got) != len(diags) {
+		t.Fatalf("got %d diagnostics, want %d", len(got), len(diags))
+	}
+	for i := range diags {
+		if !reflect.DeepEqual(got[i], diags[i]) {
+			t.Errorf("diagnostic %d round-tripped incorrectly:\n got:  %#v\n want: %#v", i, got[i], diags[i])
+		}
+	}
+}
+
+// TestDiagnosticsRoundTrip_EmptyStringIndexKey guards against IndexStep with
+// an empty string key (e.g. foo[""]) being sniffed as an int index because
+// its ElementKeyString field is also the empty string.
+func TestDiagnosticsRoundTrip_EmptyStringIndexKey(t *testing.T) {
+	diags := tfdiags.Diagnostics{
+		{
+			Summary: "empty key",
+			AttributePath: cty.Path{
+				cty.IndexStep{Key: cty.StringVal("")},
+			},
+		},
+	}
+
+	got := ToDiagnostics(NewDiagnostics(diags))
+
+	step := got[0].AttributePath[0]
+	indexStep, ok := step.(cty.IndexStep)
+	if !ok {
+		t.Fatalf("step is %T, want cty.IndexStep", step)
+	}
+	if indexStep.Key.Type() != cty.String {
+		t.Fatalf("index key type is %s, want cty.String", indexStep.Key.Type().FriendlyName())
+	}
+	if indexStep.Key.AsString() != "" {
+		t.Fatalf("index key is %q, want empty string", indexStep.Key.AsString())
+	}
+}
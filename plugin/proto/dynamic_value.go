@@ -0,0 +1,51 @@
+package proto
+
+import (
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"github.com/zclconf/go-cty/cty/msgpack"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DynamicValue carries a cty.Value across the wire as msgpack (authoritative)
+// plus a JSON copy for debug logging, left empty when the value is not wholly
+// known since JSON can't represent unknowns. It carries no cty.Type of its own:
+// both encodings need one to decode, so callers thread the owning resource
+// or data source's implied type alongside it instead.
+type DynamicValue struct {
+	Msgpack []byte
+	Json    []byte
+}
+
+// NewDynamicValue encodes val against ty, so ambiguous values (e.g. an
+// unknown number vs. an unknown string) round-trip correctly.
+func NewDynamicValue(val cty.Value, ty cty.Type) (*DynamicValue, error) {
+	mp, err := msgpack.Marshal(val, ty)
+	if err != nil {
+		return nil, err
+	}
+
+	var js []byte
+	if val.IsWhollyKnown() {
+		js, err = ctyjson.Marshal(val, ty)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &DynamicValue{
+		Msgpack: mp,
+		Json:    js,
+	}, nil
+}
+
+// Decode reconstructs the cty.Value carried by d, interpreting the wire
+// bytes according to ty. The msgpack encoding is authoritative; Json is
+// never consulted here.
+func (d *DynamicValue) Decode(ty cty.Type) (cty.Value, error) {
+	if d == nil {
+		return cty.NilVal, nil
+	}
+
+	return msgpack.Unmarshal(d.Msgpack, ty)
+}
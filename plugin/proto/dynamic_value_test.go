@@ -0,0 +1,46 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDynamicValueRoundTrip(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"name":  cty.String,
+		"count": cty.Number,
+		"tags":  cty.List(cty.String),
+	})
+
+	val := cty.ObjectVal(map[string]cty.Value{
+		"name":  cty.StringVal("widget"),
+		"count": cty.NumberIntVal(3),
+		"tags":  cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+	})
+
+	dv, err := NewDynamicValue(val, ty)
+	if err != nil {
+		t.Fatalf("NewDynamicValue: %s", err)
+	}
+
+	got, err := dv.Decode(ty)
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+
+	if !got.RawEquals(val) {
+		t.Fatalf("round-tripped value differs:\n got:  %#v\n want: %#v", got, val)
+	}
+}
+
+func TestDynamicValueDecodeNil(t *testing.T) {
+	var dv *DynamicValue
+	got, err := dv.Decode(cty.String)
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+	if got != cty.NilVal {
+		t.Fatalf("expected cty.NilVal for a nil DynamicValue, got %#v", got)
+	}
+}
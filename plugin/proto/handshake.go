@@ -0,0 +1,42 @@
+package proto
+
+// Capability bits exchanged during the Handshake RPC. GRPCResourceProvider
+// acts only on CapTypedValues, refusing ImportState when it is unset; the
+// other bits are reported but do not change its behaviour.
+const (
+	// CapTypedValues: attributes are typed DynamicValue payloads, not
+	// flattened JSON strings.
+	CapTypedValues uint64 = 1 << iota
+	// CapStreaming: Apply and ReadDataApply are server-streaming RPCs.
+	CapStreaming
+	// CapPrivateState: the opaque Private blob round-trips across Diff,
+	// Apply, and Refresh.
+	CapPrivateState
+	// CapDiagnostics: Validate and friends return structured Diagnostics
+	// instead of flat warning/error strings.
+	CapDiagnostics
+)
+
+// HandshakeRequest is sent by the plugin host to introduce itself before
+// any other RPC is made.
+type HandshakeRequest struct {
+	ProtocolVersion int64
+}
+
+// HandshakeResponse reports the provider's version and supported
+// capabilities, and echoes back which of those the server side negotiated.
+type HandshakeResponse struct {
+	ProtocolVersion       int64
+	ProviderVersion       string
+	SupportedCapabilities uint64
+	ServerCapabilities    uint64
+}
+
+// Capabilities is the negotiated result of a Handshake call, cached by
+// GRPCResourceProvider.
+type Capabilities struct {
+	ProtocolVersion int64
+	ProviderVersion string
+	Supported       uint64
+	Server          uint64
+}
@@ -0,0 +1,55 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestInstanceStatePrivateRoundTrip(t *testing.T) {
+	ty := cty.Object(map[string]cty.Type{
+		"id": cty.String,
+	})
+
+	s := &terraform.InstanceState{
+		ID:         "widget-1",
+		Attributes: map[string]string{"id": "widget-1"},
+		Private:    []byte(`{"retries":2}`),
+	}
+
+	wire, err := NewInstanceState(s, ty)
+	if err != nil {
+		t.Fatalf("NewInstanceState: %s", err)
+	}
+
+	got, err := wire.TFInstanceState(ty)
+	if err != nil {
+		t.Fatalf("TFInstanceState: %s", err)
+	}
+
+	if string(got.Private) != string(s.Private) {
+		t.Fatalf("Private round-tripped incorrectly: got %q, want %q", got.Private, s.Private)
+	}
+}
+
+func TestInstanceDiffPrivateRoundTrip(t *testing.T) {
+	d := &terraform.InstanceDiff{
+		Private: []byte(`{"cookie":"abc123"}`),
+	}
+
+	wire, err := NewInstanceDiff(d)
+	if err != nil {
+		t.Fatalf("NewInstanceDiff: %s", err)
+	}
+
+	got, err := wire.TFInstanceDiff()
+	if err != nil {
+		t.Fatalf("TFInstanceDiff: %s", err)
+	}
+
+	if string(got.Private) != string(d.Private) {
+		t.Fatalf("Private round-tripped incorrectly: got %q, want %q", got.Private, d.Private)
+	}
+}
@@ -3,7 +3,11 @@ package proto
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/hashicorp/terraform/hcl2shim"
 	"github.com/hashicorp/terraform/terraform"
 )
 
@@ -14,28 +18,28 @@ import (
 
 // marshalMap marshals the interface{}s into json to ensure that the payload is
 // serializable over grpc
-func marshalMap(m map[string]interface{}) map[string][]byte {
+func marshalMap(m map[string]interface{}) (map[string][]byte, error) {
 	if m == nil {
-		return nil
+		return nil, nil
 	}
 
 	n := make(map[string][]byte)
 	for k, v := range m {
 		js, err := json.Marshal(v)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("marshaling %q: %s", k, err)
 		}
 		n[k] = js
 	}
 
-	return n
+	return n, nil
 }
 
 // unmarshalMap unmarshals the json data into an empty interface{} for use in
 // the terraform package
-func unmarshalMap(m map[string][]byte) map[string]interface{} {
+func unmarshalMap(m map[string][]byte) (map[string]interface{}, error) {
 	if m == nil {
-		return nil
+		return nil, nil
 	}
 
 	n := make(map[string]interface{})
@@ -44,32 +48,69 @@ func unmarshalMap(m map[string][]byte) map[string]interface{} {
 		var i interface{}
 		err := json.Unmarshal(v, &i)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("unmarshaling %q: %s", k, err)
 		}
 		n[k] = i
 	}
 
-	return n
+	return n, nil
 }
 
-func NewResourceConfig(c *terraform.ResourceConfig) *ResourceConfig {
+// NewResourceConfig converts c to its wire representation, encoding the
+// config's values as a typed DynamicValue against ty rather than flattening
+// them to untyped JSON. ty is the cty.Type implied by the resource's schema,
+// as obtained from GetSchema.
+func NewResourceConfig(c *terraform.ResourceConfig, ty cty.Type) (*ResourceConfig, error) {
 	if c == nil {
-		return nil
+		return nil, nil
+	}
+
+	val, err := hcl2shim.HCL2ValueFromConfigValue(c.Config, ty)
+	if err != nil {
+		return nil, err
+	}
+
+	dv, err := NewDynamicValue(val, ty)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := marshalMap(c.Raw)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ResourceConfig{
 		ComputedKeys: c.ComputedKeys,
-		Raw:          marshalMap(c.Raw),
-		Config:       marshalMap(c.Config),
-	}
+		Raw:          raw,
+		Value:        dv,
+	}, nil
 }
 
-func (c *ResourceConfig) TFResourceConfig() *terraform.ResourceConfig {
+func (c *ResourceConfig) TFResourceConfig(ty cty.Type) (*terraform.ResourceConfig, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	raw, err := unmarshalMap(c.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	config := raw
+	if c.Value != nil {
+		val, err := c.Value.Decode(ty)
+		if err != nil {
+			return nil, err
+		}
+		config = hcl2shim.ConfigValueFromHCL2(val)
+	}
+
 	return &terraform.ResourceConfig{
 		ComputedKeys: c.ComputedKeys,
-		Raw:          unmarshalMap(c.Raw),
-		Config:       unmarshalMap(c.Config),
-	}
+		Raw:          raw,
+		Config:       config,
+	}, nil
 }
 
 func NewInstanceInfo(i *terraform.InstanceInfo) *InstanceInfo {
@@ -108,9 +149,12 @@ func (s *EphemeralState) TFEphemeralState() terraform.EphemeralState {
 	return es
 }
 
-func NewInstanceState(s *terraform.InstanceState) *InstanceState {
+// NewInstanceState converts s to its wire representation, encoding
+// s.Attributes as a typed DynamicValue against ty alongside the legacy
+// stringified Attributes map.
+func NewInstanceState(s *terraform.InstanceState, ty cty.Type) (*InstanceState, error) {
 	if s == nil {
-		return nil
+		return nil, nil
 	}
 
 	attrs := make(map[string][]byte)
@@ -122,21 +166,33 @@ func NewInstanceState(s *terraform.InstanceState) *InstanceState {
 
 	meta, err := json.Marshal(s.Meta)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("marshaling instance state meta: %s", err)
+	}
+
+	val, err := hcl2shim.HCL2ValueFromFlatmap(s.Attributes, ty)
+	if err != nil {
+		return nil, err
+	}
+
+	dv, err := NewDynamicValue(val, ty)
+	if err != nil {
+		return nil, err
 	}
 
 	return &InstanceState{
 		Id:         s.ID,
 		Attributes: attrs,
+		Value:      dv,
 		Ephemeral:  NewEphemeralState(s.Ephemeral),
 		Meta:       meta,
+		Private:    s.Private,
 		Tainted:    s.Tainted,
-	}
+	}, nil
 }
 
-func (s *InstanceState) TFInstanceState() *terraform.InstanceState {
+func (s *InstanceState) TFInstanceState(ty cty.Type) (*terraform.InstanceState, error) {
 	if s == nil {
-		return nil
+		return nil, nil
 	}
 
 	var attrs map[string]string
@@ -148,11 +204,23 @@ func (s *InstanceState) TFInstanceState() *terraform.InstanceState {
 		attrs[k] = string(v)
 	}
 
+	if s.Value != nil {
+		val, err := s.Value.Decode(ty)
+		if err != nil {
+			return nil, err
+		}
+
+		attrs, err = hcl2shim.FlatmapValueFromHCL2(val)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var meta map[string]interface{}
 	if s.Meta != nil {
 		err := json.Unmarshal(s.Meta, &meta)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("unmarshaling instance state meta: %s", err)
 		}
 	}
 
@@ -161,18 +229,22 @@ func (s *InstanceState) TFInstanceState() *terraform.InstanceState {
 		Attributes: attrs,
 		Ephemeral:  s.Ephemeral.TFEphemeralState(),
 		Meta:       meta,
+		Private:    s.Private,
 		Tainted:    s.Tainted,
-	}
+	}, nil
 }
 
-func NewResourceAttrDiff(d *terraform.ResourceAttrDiff) *ResourceAttrDiff {
+// NewResourceAttrDiff converts d to its wire representation. Old/New stay
+// flatmapped strings, since a cty.Type can't be derived for an arbitrary
+// flattened attribute path.
+func NewResourceAttrDiff(d *terraform.ResourceAttrDiff) (*ResourceAttrDiff, error) {
 	if d == nil {
-		return nil
+		return nil, nil
 	}
 
 	newExtra, err := json.Marshal(d.NewExtra)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("marshaling NewExtra for attribute diff: %s", err)
 	}
 
 	return &ResourceAttrDiff{
@@ -184,19 +256,19 @@ func NewResourceAttrDiff(d *terraform.ResourceAttrDiff) *ResourceAttrDiff {
 		RequiresNew: d.RequiresNew,
 		Sensitive:   d.Sensitive,
 		Type:        DiffAttrType(d.Type),
-	}
+	}, nil
 }
 
-func (d *ResourceAttrDiff) TFResourceAttrDiff() *terraform.ResourceAttrDiff {
+func (d *ResourceAttrDiff) TFResourceAttrDiff() (*terraform.ResourceAttrDiff, error) {
 	if d == nil {
-		return nil
+		return nil, nil
 	}
 
 	var newExtra interface{}
 	if d.NewExtra != nil {
 		err := json.Unmarshal(d.NewExtra, &newExtra)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("unmarshaling NewExtra for attribute diff: %s", err)
 		}
 	}
 
@@ -209,12 +281,14 @@ func (d *ResourceAttrDiff) TFResourceAttrDiff() *terraform.ResourceAttrDiff {
 		RequiresNew: d.RequiresNew,
 		Sensitive:   d.Sensitive,
 		Type:        terraform.DiffAttrType(d.Type),
-	}
+	}, nil
 }
 
-func NewInstanceDiff(d *terraform.InstanceDiff) *InstanceDiff {
+// NewInstanceDiff converts d to its wire representation. Unlike
+// NewInstanceState, it takes no cty.Type: per-attribute diffs aren't typed.
+func NewInstanceDiff(d *terraform.InstanceDiff) (*InstanceDiff, error) {
 	if d == nil {
-		return nil
+		return nil, nil
 	}
 
 	// make sure nil is conveyed
@@ -223,12 +297,16 @@ func NewInstanceDiff(d *terraform.InstanceDiff) *InstanceDiff {
 		attrs = make(map[string]*ResourceAttrDiff)
 	}
 	for k, attr := range d.Attributes {
-		attrs[k] = NewResourceAttrDiff(attr)
+		attrDiff, err := NewResourceAttrDiff(attr)
+		if err != nil {
+			return nil, err
+		}
+		attrs[k] = attrDiff
 	}
 
 	meta, err := json.Marshal(d.Meta)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("marshaling instance diff meta: %s", err)
 	}
 
 	return &InstanceDiff{
@@ -237,12 +315,13 @@ func NewInstanceDiff(d *terraform.InstanceDiff) *InstanceDiff {
 		DestroyDeposed: d.DestroyDeposed,
 		DestroyTainted: d.DestroyTainted,
 		Meta:           meta,
-	}
+		Private:        d.Private,
+	}, nil
 }
 
-func (d *InstanceDiff) TFInstanceDiff() *terraform.InstanceDiff {
+func (d *InstanceDiff) TFInstanceDiff() (*terraform.InstanceDiff, error) {
 	if d == nil {
-		return nil
+		return nil, nil
 	}
 
 	// make sure nil is conveyed
@@ -251,14 +330,18 @@ func (d *InstanceDiff) TFInstanceDiff() *terraform.InstanceDiff {
 		attrs = make(map[string]*terraform.ResourceAttrDiff)
 	}
 	for k, attr := range d.Attributes {
-		attrs[k] = attr.TFResourceAttrDiff()
+		attrDiff, err := attr.TFResourceAttrDiff()
+		if err != nil {
+			return nil, err
+		}
+		attrs[k] = attrDiff
 	}
 
 	var meta map[string]interface{}
 	if d.Meta != nil {
 		err := json.Unmarshal(d.Meta, &meta)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("unmarshaling instance diff meta: %s", err)
 		}
 	}
 
@@ -268,26 +351,62 @@ func (d *InstanceDiff) TFInstanceDiff() *terraform.InstanceDiff {
 		DestroyDeposed: d.DestroyDeposed,
 		DestroyTainted: d.DestroyTainted,
 		Meta:           meta,
-	}
+		Private:        d.Private,
+	}, nil
 }
 
-func NewImportStateResponse(s []*terraform.InstanceState) *ImportStateResponse {
+func NewImportStateResponse(s []*terraform.InstanceState, ty cty.Type) (*ImportStateResponse, error) {
 	r := &ImportStateResponse{}
 	for _, state := range s {
-		r.State = append(r.State, NewInstanceState(state))
+		is, err := NewInstanceState(state, ty)
+		if err != nil {
+			return nil, err
+		}
+		r.State = append(r.State, is)
 	}
 
-	return r
+	return r, nil
 }
 
-func (r *ImportStateResponse) TFInstanceStates() []*terraform.InstanceState {
+func (r *ImportStateResponse) TFInstanceStates(ty cty.Type) ([]*terraform.InstanceState, error) {
 	var states []*terraform.InstanceState
 
 	for _, s := range r.State {
-		states = append(states, s.TFInstanceState())
+		state, err := s.TFInstanceState(ty)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
 	}
 
-	return states
+	return states, nil
+}
+
+// NewValidateResponse builds the legacy warnings/errors shape of
+// ValidateResponse, still used by GRPCResourceProvisioner, which predates
+// the switch to structured diagnostics.
+func NewValidateResponse(w []string, e []error) *ValidateResponse {
+	resp := &ValidateResponse{
+		Warnings: w,
+	}
+	for _, err := range e {
+		resp.Errors = append(resp.Errors, err.Error())
+	}
+	return resp
+}
+
+// ErrorList is a convenience method to convert the array of protobuf Error
+// messages to a Go []error.
+func (r *ValidateResponse) ErrorList() []error {
+	if r == nil || len(r.Errors) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(r.Errors))
+	for i := range r.Errors {
+		errs[i] = errors.New(r.Errors[i])
+	}
+	return errs
 }
 
 func NewDataSourcesResponse(ds []terraform.DataSource) *DataSourcesResponse {
@@ -337,27 +456,3 @@ func (r *ResourcesResponse) TFResources() []terraform.ResourceType {
 	}
 	return rs
 }
-
-func NewValidateResponse(w []string, e []error) *ValidateResponse {
-	resp := &ValidateResponse{
-		Warnings: w,
-	}
-	for _, err := range e {
-		resp.Errors = append(resp.Errors, err.Error())
-	}
-	return resp
-}
-
-// ErrorList is a convenience method to convert the array of protobuf Error
-// messages to a Go []error.
-func (r *ValidateResponse) ErrorList() []error {
-	if r == nil || len(r.Errors) == 0 {
-		return nil
-	}
-
-	errs := make([]error, len(r.Errors))
-	for i := range r.Errors {
-		errs[i] = errors.New(r.Errors[i])
-	}
-	return errs
-}
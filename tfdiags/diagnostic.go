@@ -0,0 +1,93 @@
+// Package tfdiags defines a common representation of warnings and errors
+// that can carry an optional attribute path and source range, so that a
+// diagnostic raised deep in a provider or in core can point the user at the
+// exact attribute and file position responsible rather than forcing
+// downstream callers to parse an error string.
+package tfdiags
+
+import (
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Severity describes how serious a Diagnostic is. Error diagnostics halt
+// the operation that produced them; Warning diagnostics do not.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+// SourcePos is a single line/column/byte position within a source file.
+type SourcePos struct {
+	Line, Column, Byte int
+}
+
+// SourceRange identifies the span of a source file a Diagnostic's Subject
+// refers to.
+type SourceRange struct {
+	Filename   string
+	Start, End SourcePos
+}
+
+// Diagnostic is a single warning or error, optionally scoped to a specific
+// attribute (AttributePath) and source position (Subject).
+type Diagnostic struct {
+	Severity      Severity
+	Summary       string
+	Detail        string
+	AttributePath cty.Path
+	Subject       *SourceRange
+}
+
+func (d *Diagnostic) Error() string {
+	if d.Detail == "" {
+		return d.Summary
+	}
+	return d.Summary + ": " + d.Detail
+}
+
+// Diagnostics is an ordered collection of Diagnostic values, in the order
+// they were raised.
+type Diagnostics []*Diagnostic
+
+// Append adds diag to the end of the list, returning the updated slice in
+// the same manner as the builtin append.
+func (d Diagnostics) Append(diag *Diagnostic) Diagnostics {
+	return append(d, diag)
+}
+
+// HasErrors reports whether any diagnostic in the collection is an Error.
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Err flattens the collection's error-severity diagnostics into a single
+// error, or returns nil if there are none. Warnings are not included.
+func (d Diagnostics) Err() error {
+	if !d.HasErrors() {
+		return nil
+	}
+
+	var msgs []string
+	for _, diag := range d {
+		if diag.Severity == Error {
+			msgs = append(msgs, diag.Error())
+		}
+	}
+
+	return errorsList(msgs)
+}
+
+type errorsList []string
+
+func (e errorsList) Error() string {
+	return strings.Join([]string(e), "\n")
+}